@@ -1,10 +1,18 @@
 package badssl
 
 import (
+	"bytes"
+	"crypto"
 	"crypto/rand"
+	"crypto/sha1"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"io"
 	"math/big"
+	"net"
+	"net/url"
 	"time"
 )
 
@@ -12,14 +20,87 @@ const (
 	// RootCAKeyUsage is the key usage setting used for creating the root
 	// certificate authority. It is exposed for reference.
 	RootCAKeyUsage = x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign
+	// ServerKeyUsage is the key usage setting used for creating leaf
+	// (server) certificates. It is exposed for reference.
+	ServerKeyUsage = x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature
 )
 
+// oidTLSFeature is the OID of the TLS Feature extension (RFC 7633).
+var oidTLSFeature = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 24}
+
+// mustStapleFeatureValue is the DER encoding of a TLS Feature extension
+// requesting only status_request (OCSP Must-Staple, feature value 5).
+var mustStapleFeatureValue = []byte{0x30, 0x03, 0x02, 0x01, 0x05}
+
 // CertOptions is used to configure a certificate during certificate creation.
 type CertOptions struct {
 	// ValidFor is the amount of time that a certificate will be valid for.
 	ValidFor time.Duration
 	// CommonName is the common name associated with the certificate subject.
+	// It is a shorthand for Subject.CommonName: if Subject.CommonName is
+	// unset, CommonName is used in its place.
 	CommonName string
+	// Subject is the full certificate subject (Organization, OU, Country,
+	// etc). Subject.CommonName takes precedence over CommonName when both
+	// are set.
+	Subject pkix.Name
+	// DNSNames is the list of DNS subject alternative names.
+	DNSNames []string
+	// IPAddresses is the list of IP subject alternative names.
+	IPAddresses []net.IP
+	// EmailAddresses is the list of email subject alternative names.
+	EmailAddresses []string
+	// URIs is the list of URI subject alternative names.
+	URIs []*url.URL
+	// ClientAuth additionally marks a leaf certificate as valid for TLS
+	// client authentication (ExtKeyUsageClientAuth). It has no effect on
+	// certificate authorities.
+	ClientAuth bool
+	// OCSPServers is the Authority Information Access OCSP responder list
+	// for a leaf certificate.
+	OCSPServers []string
+	// IssuingCertificateURLs is the Authority Information Access CA Issuers
+	// list for a leaf certificate.
+	IssuingCertificateURLs []string
+	// CRLDistributionPoints is the CRL Distribution Points extension for a
+	// leaf certificate.
+	CRLDistributionPoints []string
+	// MustStaple marks a leaf certificate with the TLS Feature (RFC 7633)
+	// extension requesting OCSP stapling (status_request).
+	MustStaple bool
+	// MaxPathLen is the number of additional intermediate CAs the new
+	// intermediate is itself allowed to issue below it (0, the default,
+	// means it may only issue leaf certificates). It is only consulted by
+	// NewIntermediate, which returns an error if it exceeds the issuing
+	// authority's own remaining path length budget.
+	MaxPathLen int
+}
+
+// subject returns the full certificate subject for o, folding the
+// shorthand CommonName field into Subject when Subject.CommonName is unset.
+func (o CertOptions) subject() pkix.Name {
+	subj := o.Subject
+	if subj.CommonName == "" {
+		subj.CommonName = o.CommonName
+	}
+	return subj
+}
+
+// withAutoSAN derives DNSNames/IPAddresses from CommonName when no DNS or IP
+// SANs were explicitly provided (email and URI SANs are not considered),
+// matching the behavior of mkcert-style tools:
+// a CommonName that parses as an IP address becomes an IP SAN, otherwise
+// it becomes a DNS SAN.
+func (o CertOptions) withAutoSAN() CertOptions {
+	if len(o.DNSNames) > 0 || len(o.IPAddresses) > 0 || o.CommonName == "" {
+		return o
+	}
+	if ip := net.ParseIP(o.CommonName); ip != nil {
+		o.IPAddresses = []net.IP{ip}
+	} else {
+		o.DNSNames = []string{o.CommonName}
+	}
+	return o
 }
 
 // Authority is an interface that represents a certificate authority.
@@ -27,34 +108,55 @@ type CertOptions struct {
 // child certificates.
 type Authority interface {
 	Certificate
-	// NewCert creates a new certificate that is signed by Authority.
-	NewCert(*PrivateKey, CertOptions) (Certificate, error)
+	// NewCert creates a new leaf certificate that is signed by Authority.
+	NewCert(*PrivateKey, CertOptions, ...Option) (Certificate, error)
+	// NewIntermediate creates a new intermediate certificate authority
+	// that is signed by Authority. Certificates issued by the returned
+	// Authority chain root -> Authority -> intermediate -> leaf.
+	NewIntermediate(CertOptions, ...Option) (Authority, error)
+	// SignCSR validates and signs a certificate signing request, issuing
+	// a certificate bound to the requester's public key.
+	SignCSR(*CSR, CertOptions, ...Option) (Certificate, error)
+	// SignOCSPResponse signs an OCSP response for cert, so Authority can
+	// double as a test OCSP responder.
+	SignOCSPResponse(cert Certificate, status int, thisUpdate, nextUpdate time.Time) ([]byte, error)
 }
 
 type authority struct {
 	c *certificate
+	// chain holds the DER encoding of this authority's own certificate and
+	// all of its issuers, up to (but not including) the root. It is empty
+	// when this authority is itself the root. New certificates issued by
+	// this authority inherit chain as their issuer chain.
+	chain []DER
+	// env holds this authority's default clock/randomness/backdating
+	// settings, inherited by certificates and intermediates it issues
+	// unless overridden per call.
+	env env
 }
 
 // NewAuthority generates a new (root) certificate authority with private key
 // k and configuration options o. If k is nil, a new private key will be
-// generated.
-func NewAuthority(k *PrivateKey, o CertOptions) (a Authority, err error) {
+// generated. opts configure the clock, randomness sources, and backdating
+// used for this authority and, by default, everything it issues.
+func NewAuthority(k *PrivateKey, o CertOptions, opts ...Option) (a Authority, err error) {
+	e := applyOptions(newEnv(), opts)
 	if k == nil {
-		if k, err = NewPrivateKey(); err != nil {
+		if k, err = newPrivateKeyFromRand(RSA2048, e.keyRand); err != nil {
 			return
 		}
 	}
 	var cert *x509.Certificate
-	if cert, err = newAuthorityCertificate(o); err != nil {
+	if cert, err = newAuthorityCertificate(o, k.signer.Public(), e); err != nil {
 		return
 	}
 	var der DER
 	der, err = x509.CreateCertificate(
-		rand.Reader,
+		e.signRand,
 		cert,
 		cert,
-		&k.privateKey.PublicKey,
-		k.privateKey,
+		k.signer.Public(),
+		k.signer,
 	)
 	if err != nil {
 		return
@@ -65,30 +167,45 @@ func NewAuthority(k *PrivateKey, o CertOptions) (a Authority, err error) {
 			privateKey: k,
 			der:        der,
 		},
+		env: e,
 	}
 	a, err = auth.Reload()
 	return
 }
 
 // ParseAuthorityPEM parses and loads a certificate authority from PEM data.
-func ParseAuthorityPEM(data PEM, k *PrivateKey) (a Authority, err error) {
+func ParseAuthorityPEM(data PEM, k *PrivateKey, opts ...Option) (a Authority, err error) {
 	var c *certificate
 	if c, err = parseCertificatePEM(data, k); err != nil {
 		return
 	}
-	a = &authority{c: c}
-	return
+	return authorityFromCertificate(c, opts), nil
 }
 
 // ParseAuthorityDER parses and loads a certificate authority from
 // ASN.1 DER data.
-func ParseAuthorityDER(data DER, k *PrivateKey) (a Authority, err error) {
+func ParseAuthorityDER(data DER, k *PrivateKey, opts ...Option) (a Authority, err error) {
 	var c *certificate
 	if c, err = parseCertificateDER(data, k); err != nil {
 		return
 	}
-	a = &authority{c: c}
-	return
+	return authorityFromCertificate(c, opts), nil
+}
+
+// authorityFromCertificate builds an authority around c, deriving chain from
+// c's parsed issuers. A self-signed c is treated as the root, whose chain is
+// empty; otherwise c is treated as an intermediate and is prepended to its
+// own chain.
+func authorityFromCertificate(c *certificate, opts []Option) *authority {
+	e := applyOptions(newEnv(), opts)
+	if isSelfSigned(c.cert) {
+		return &authority{c: c, chain: c.issuers, env: e}
+	}
+	return &authority{c: c, chain: append([]DER{c.der}, c.issuers...), env: e}
+}
+
+func isSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawIssuer, cert.RawSubject)
 }
 
 func (a *authority) GetPEM() (PEM, error) {
@@ -99,26 +216,33 @@ func (a *authority) GetKey() *PrivateKey {
 	return a.c.GetKey()
 }
 
+func (a *authority) GetChainPEM() (PEM, error) {
+	return a.c.GetChainPEM()
+}
+
 func (a *authority) NewCert(
 	k *PrivateKey,
 	o CertOptions,
+	opts ...Option,
 ) (c Certificate, err error) {
+	e := applyOptions(a.env, opts)
 	if k == nil {
-		if k, err = NewPrivateKey(); err != nil {
+		if k, err = newPrivateKeyFromRand(RSA2048, e.keyRand); err != nil {
 			return
 		}
 	}
 	var cert *x509.Certificate
-	if cert, err = newServerCertificate(o); err != nil {
+	if cert, err = newServerCertificate(o.withAutoSAN(), k.signer.Public(), e); err != nil {
 		return
 	}
+	cert.AuthorityKeyId = a.c.cert.SubjectKeyId
 	var der DER
 	der, err = x509.CreateCertificate(
-		rand.Reader,
+		e.signRand,
 		cert,
 		a.c.cert,
-		&k.privateKey.PublicKey,
-		a.c.privateKey.privateKey,
+		k.signer.Public(),
+		a.c.privateKey.signer,
 	)
 	if err != nil {
 		return
@@ -127,55 +251,204 @@ func (a *authority) NewCert(
 		cert:       cert,
 		privateKey: k,
 		der:        der,
+		issuers:    a.chain,
 	}
 	return
 }
 
+// NewIntermediate generates a new intermediate certificate authority signed
+// by a. By default (o.MaxPathLen == 0) the intermediate is restricted to
+// issuing leaf certificates only; o.MaxPathLen may request that it also be
+// allowed to issue further intermediates, up to a's own remaining path
+// length budget. NewIntermediate returns an error if a's certificate is
+// already constrained to MaxPathLen 0, or if o.MaxPathLen would exceed what
+// a's budget allows. opts override a's env for the intermediate and become
+// its default env for anything it subsequently issues.
+func (a *authority) NewIntermediate(o CertOptions, opts ...Option) (ia Authority, err error) {
+	maxPathLen, err := a.childMaxPathLen(o.MaxPathLen)
+	if err != nil {
+		return nil, err
+	}
+	e := applyOptions(a.env, opts)
+	k, err := newPrivateKeyFromRand(RSA2048, e.keyRand)
+	if err != nil {
+		return nil, err
+	}
+	var cert *x509.Certificate
+	if cert, err = newIntermediateCertificate(o, maxPathLen, k.signer.Public(), e); err != nil {
+		return nil, err
+	}
+	cert.AuthorityKeyId = a.c.cert.SubjectKeyId
+	var der DER
+	der, err = x509.CreateCertificate(
+		e.signRand,
+		cert,
+		a.c.cert,
+		k.signer.Public(),
+		a.c.privateKey.signer,
+	)
+	if err != nil {
+		return nil, err
+	}
+	c := &certificate{
+		cert:       cert,
+		privateKey: k,
+		der:        der,
+		issuers:    a.chain,
+	}
+	newAuth := &authority{c: c, chain: append([]DER{der}, a.chain...), env: e}
+	return newAuth.Reload()
+}
+
+// childMaxPathLen validates that a is permitted to issue an intermediate
+// requesting the given MaxPathLen, and returns the MaxPathLen the child
+// should actually be issued with. a's own certificate constrains how deep
+// the chain below it may go: a MaxPathLen of 0 means a may not issue any
+// further intermediates at all, and any other value caps how large a
+// child's own MaxPathLen may be.
+func (a *authority) childMaxPathLen(requested int) (int, error) {
+	if !a.c.cert.MaxPathLenZero && a.c.cert.MaxPathLen <= 0 {
+		// Unconstrained (e.g. the root authority): honor the request as-is.
+		return requested, nil
+	}
+	if a.c.cert.MaxPathLen < 1 {
+		return 0, fmt.Errorf("authority %q has MaxPathLen=0 and cannot issue further intermediate CAs", a.c.cert.Subject.CommonName)
+	}
+	if requested > a.c.cert.MaxPathLen-1 {
+		return 0, fmt.Errorf("requested MaxPathLen %d exceeds authority %q's remaining path length budget (%d)", requested, a.c.cert.Subject.CommonName, a.c.cert.MaxPathLen-1)
+	}
+	return requested, nil
+}
+
 func (a *authority) Reload() (Authority, error) {
-	return ParseAuthorityDER(a.c.der, a.c.privateKey)
+	cert, err := a.env.parseCertificate(a.c.der)
+	if err != nil {
+		return nil, err
+	}
+	c := &certificate{
+		cert:       cert,
+		privateKey: a.c.privateKey,
+		der:        a.c.der,
+		issuers:    a.c.issuers,
+	}
+	return &authority{c: c, chain: a.chain, env: a.env}, nil
 }
 
-func newAuthorityCertificate(o CertOptions) (*x509.Certificate, error) {
-	sn, err := randomSerialNumber()
+func subjectKeyID(pub crypto.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
 	if err != nil {
 		return nil, err
 	}
-	notBefore := time.Now()
+	sum := sha1.Sum(der)
+	return sum[:], nil
+}
+
+func newAuthorityCertificate(o CertOptions, pub crypto.PublicKey, e env) (*x509.Certificate, error) {
+	sn, err := randomSerialNumber(e.serialRand)
+	if err != nil {
+		return nil, err
+	}
+	ski, err := subjectKeyID(pub)
+	if err != nil {
+		return nil, err
+	}
+	notBefore := e.notBefore()
 	notAfter := notBefore.Add(o.ValidFor)
 	cert := &x509.Certificate{
 		SerialNumber:          sn,
-		Subject:               pkix.Name{CommonName: o.CommonName},
+		Subject:               o.subject(),
+		DNSNames:              o.DNSNames,
+		IPAddresses:           o.IPAddresses,
+		EmailAddresses:        o.EmailAddresses,
+		URIs:                  o.URIs,
 		NotBefore:             notBefore,
 		NotAfter:              notAfter,
 		KeyUsage:              RootCAKeyUsage,
 		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
 		BasicConstraintsValid: true,
-		IsCA: true,
+		IsCA:                  true,
+		SubjectKeyId:          ski,
 	}
 	return cert, nil
 }
 
-func newServerCertificate(o CertOptions) (*x509.Certificate, error) {
-	sn, err := randomSerialNumber()
+// newIntermediateCertificate builds a template for an intermediate CA
+// certificate with the given MaxPathLen (0 means the intermediate may only
+// issue leaf certificates, not further intermediates).
+func newIntermediateCertificate(o CertOptions, maxPathLen int, pub crypto.PublicKey, e env) (*x509.Certificate, error) {
+	sn, err := randomSerialNumber(e.serialRand)
+	if err != nil {
+		return nil, err
+	}
+	ski, err := subjectKeyID(pub)
 	if err != nil {
 		return nil, err
 	}
-	notBefore := time.Now()
+	notBefore := e.notBefore()
 	notAfter := notBefore.Add(o.ValidFor)
 	cert := &x509.Certificate{
 		SerialNumber:          sn,
-		Subject:               pkix.Name{CommonName: o.CommonName},
+		Subject:               o.subject(),
+		DNSNames:              o.DNSNames,
+		IPAddresses:           o.IPAddresses,
+		EmailAddresses:        o.EmailAddresses,
+		URIs:                  o.URIs,
 		NotBefore:             notBefore,
 		NotAfter:              notAfter,
-		KeyUsage:              0,   // TODO
-		ExtKeyUsage:           nil, // TODO
+		KeyUsage:              RootCAKeyUsage,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLen:            maxPathLen,
+		MaxPathLenZero:        maxPathLen == 0,
+		SubjectKeyId:          ski,
+	}
+	return cert, nil
+}
+
+func newServerCertificate(o CertOptions, pub crypto.PublicKey, e env) (*x509.Certificate, error) {
+	sn, err := randomSerialNumber(e.serialRand)
+	if err != nil {
+		return nil, err
+	}
+	ski, err := subjectKeyID(pub)
+	if err != nil {
+		return nil, err
+	}
+	notBefore := e.notBefore()
+	notAfter := notBefore.Add(o.ValidFor)
+	extKeyUsage := []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	if o.ClientAuth {
+		extKeyUsage = append(extKeyUsage, x509.ExtKeyUsageClientAuth)
+	}
+	cert := &x509.Certificate{
+		SerialNumber:          sn,
+		Subject:               o.subject(),
+		DNSNames:              o.DNSNames,
+		IPAddresses:           o.IPAddresses,
+		EmailAddresses:        o.EmailAddresses,
+		URIs:                  o.URIs,
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              ServerKeyUsage,
+		ExtKeyUsage:           extKeyUsage,
 		BasicConstraintsValid: true,
+		SubjectKeyId:          ski,
+		OCSPServer:            o.OCSPServers,
+		IssuingCertificateURL: o.IssuingCertificateURLs,
+		CRLDistributionPoints: o.CRLDistributionPoints,
+	}
+	if o.MustStaple {
+		cert.ExtraExtensions = append(cert.ExtraExtensions, pkix.Extension{
+			Id:    oidTLSFeature,
+			Value: mustStapleFeatureValue,
+		})
 	}
 	return cert, nil
 }
 
-func randomSerialNumber() (sn *big.Int, err error) {
+func randomSerialNumber(rnd io.Reader) (sn *big.Int, err error) {
 	snLimit := new(big.Int).Lsh(big.NewInt(1), 128)
-	sn, err = rand.Int(rand.Reader, snLimit)
+	sn, err = rand.Int(rnd, snLimit)
 	return
 }
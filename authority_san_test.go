@@ -0,0 +1,66 @@
+package badssl
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestWithAutoSANDerivesDNSFromCommonName(t *testing.T) {
+	o := CertOptions{CommonName: "example.com"}.withAutoSAN()
+	if len(o.DNSNames) != 1 || o.DNSNames[0] != "example.com" {
+		t.Fatalf("DNSNames = %v, want [example.com]", o.DNSNames)
+	}
+	if len(o.IPAddresses) != 0 {
+		t.Fatalf("IPAddresses = %v, want none", o.IPAddresses)
+	}
+}
+
+func TestWithAutoSANDerivesIPFromCommonName(t *testing.T) {
+	o := CertOptions{CommonName: "127.0.0.1"}.withAutoSAN()
+	if len(o.IPAddresses) != 1 || !o.IPAddresses[0].Equal(net.ParseIP("127.0.0.1")) {
+		t.Fatalf("IPAddresses = %v, want [127.0.0.1]", o.IPAddresses)
+	}
+	if len(o.DNSNames) != 0 {
+		t.Fatalf("DNSNames = %v, want none", o.DNSNames)
+	}
+}
+
+func TestWithAutoSANLeavesExplicitSANsAlone(t *testing.T) {
+	o := CertOptions{CommonName: "example.com", DNSNames: []string{"other.example.com"}}.withAutoSAN()
+	if len(o.DNSNames) != 1 || o.DNSNames[0] != "other.example.com" {
+		t.Fatalf("DNSNames = %v, want [other.example.com] (unchanged)", o.DNSNames)
+	}
+}
+
+func TestSubjectFallsBackToCommonName(t *testing.T) {
+	o := CertOptions{CommonName: "fallback.example.com"}
+	if got := o.subject().CommonName; got != "fallback.example.com" {
+		t.Fatalf("subject().CommonName = %q, want %q", got, "fallback.example.com")
+	}
+}
+
+func TestNewCertWiresAutoSANAndKeyUsage(t *testing.T) {
+	root, err := NewAuthority(nil, CertOptions{CommonName: "root CA", ValidFor: 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("NewAuthority: %v", err)
+	}
+	leafKey, err := NewPrivateKey(EC256)
+	if err != nil {
+		t.Fatalf("NewPrivateKey: %v", err)
+	}
+	leaf, err := root.NewCert(leafKey, CertOptions{CommonName: "leaf.example.com", ValidFor: time.Hour})
+	if err != nil {
+		t.Fatalf("NewCert: %v", err)
+	}
+	cert := parsePEMCert(t, mustGetPEM(t, leaf))
+	if len(cert.DNSNames) != 1 || cert.DNSNames[0] != "leaf.example.com" {
+		t.Fatalf("DNSNames = %v, want [leaf.example.com]", cert.DNSNames)
+	}
+	if cert.KeyUsage != ServerKeyUsage {
+		t.Fatalf("KeyUsage = %v, want %v", cert.KeyUsage, ServerKeyUsage)
+	}
+	if cert.IsCA {
+		t.Fatal("leaf certificate should not be a CA")
+	}
+}
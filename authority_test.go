@@ -0,0 +1,129 @@
+package badssl
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+)
+
+func parsePEMCert(t *testing.T, p PEM) *x509.Certificate {
+	t.Helper()
+	block, _ := pem.Decode(p)
+	if block == nil {
+		t.Fatalf("could not decode PEM block from %s", p)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("ParseCertificate: %v", err)
+	}
+	return cert
+}
+
+func mustGetPEM(t *testing.T, c Certificate) PEM {
+	t.Helper()
+	p, err := c.GetPEM()
+	if err != nil {
+		t.Fatalf("GetPEM: %v", err)
+	}
+	return p
+}
+
+// TestNestedIntermediateChainVerifies builds root -> intermediate ->
+// intermediate -> leaf, granting the first intermediate a MaxPathLen of 1,
+// and checks that the resulting chain is accepted by x509.Verify. Before
+// NewIntermediate propagated a real path-length budget, the second
+// intermediate always inherited MaxPathLen 0 regardless of what its parent
+// allowed, and this chain failed to verify.
+func TestNestedIntermediateChainVerifies(t *testing.T) {
+	root, err := NewAuthority(nil, CertOptions{CommonName: "root CA", ValidFor: 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("NewAuthority: %v", err)
+	}
+	inter1, err := root.NewIntermediate(CertOptions{
+		CommonName: "intermediate 1",
+		ValidFor:   24 * time.Hour,
+		MaxPathLen: 1,
+	})
+	if err != nil {
+		t.Fatalf("root.NewIntermediate: %v", err)
+	}
+	inter2, err := inter1.NewIntermediate(CertOptions{
+		CommonName: "intermediate 2",
+		ValidFor:   24 * time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("inter1.NewIntermediate: %v", err)
+	}
+	leafKey, err := NewPrivateKey(EC256)
+	if err != nil {
+		t.Fatalf("NewPrivateKey: %v", err)
+	}
+	leaf, err := inter2.NewCert(leafKey, CertOptions{CommonName: "leaf.example.com", ValidFor: time.Hour})
+	if err != nil {
+		t.Fatalf("inter2.NewCert: %v", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(parsePEMCert(t, mustGetPEM(t, root)))
+	intermediates := x509.NewCertPool()
+	intermediates.AddCert(parsePEMCert(t, mustGetPEM(t, inter1)))
+	intermediates.AddCert(parsePEMCert(t, mustGetPEM(t, inter2)))
+
+	leafCert := parsePEMCert(t, mustGetPEM(t, leaf))
+	chains, err := leafCert.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	if err != nil {
+		t.Fatalf("leaf certificate failed to verify: %v", err)
+	}
+	if len(chains) == 0 {
+		t.Fatal("expected at least one verified chain")
+	}
+}
+
+// TestNewIntermediateRejectsNoBudget ensures that NewIntermediate refuses to
+// issue another intermediate once the parent's own MaxPathLen is exhausted,
+// rather than silently producing a chain that cannot verify.
+func TestNewIntermediateRejectsNoBudget(t *testing.T) {
+	root, err := NewAuthority(nil, CertOptions{CommonName: "root CA", ValidFor: 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("NewAuthority: %v", err)
+	}
+	// Default MaxPathLen (0): inter1 may only issue leaf certificates.
+	inter1, err := root.NewIntermediate(CertOptions{CommonName: "intermediate 1", ValidFor: 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("root.NewIntermediate: %v", err)
+	}
+	if _, err := inter1.NewIntermediate(CertOptions{CommonName: "intermediate 2", ValidFor: 24 * time.Hour}); err == nil {
+		t.Fatal("expected an error issuing an intermediate under an authority with MaxPathLen 0, got nil")
+	}
+}
+
+// TestNewIntermediateRejectsExceedingBudget ensures a requested MaxPathLen
+// that would outgrow the parent's own remaining budget is rejected.
+func TestNewIntermediateRejectsExceedingBudget(t *testing.T) {
+	root, err := NewAuthority(nil, CertOptions{CommonName: "root CA", ValidFor: 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("NewAuthority: %v", err)
+	}
+	inter1, err := root.NewIntermediate(CertOptions{
+		CommonName: "intermediate 1",
+		ValidFor:   24 * time.Hour,
+		MaxPathLen: 1,
+	})
+	if err != nil {
+		t.Fatalf("root.NewIntermediate: %v", err)
+	}
+	// inter1's remaining budget for children is 0, so requesting 1 here
+	// should be rejected rather than silently truncated.
+	if _, err := inter1.NewIntermediate(CertOptions{
+		CommonName: "intermediate 2",
+		ValidFor:   24 * time.Hour,
+		MaxPathLen: 1,
+	}); err == nil {
+		t.Fatal("expected an error requesting MaxPathLen beyond the parent's remaining budget, got nil")
+	}
+}
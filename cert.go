@@ -12,6 +12,9 @@ import (
 // private key. Certificate extends the Encodable interface.
 type Certificate interface {
 	GetKey() *PrivateKey
+	// GetChainPEM encodes the certificate in PEM format followed by every
+	// issuer certificate up to (but not including) the root, in order.
+	GetChainPEM() (PEM, error)
 	Encodable
 }
 
@@ -19,9 +22,15 @@ type certificate struct {
 	privateKey *PrivateKey
 	cert       *x509.Certificate
 	der        DER
+	// issuers holds the DER encoding of every issuer of this certificate,
+	// nearest first, up to (but not including) the root CA.
+	issuers []DER
 }
 
-// ParseCertificatePEM parses and loads a certificate from PEM data.
+// ParseCertificatePEM parses and loads a certificate from PEM data. data may
+// contain additional PEM blocks following the certificate itself, which are
+// treated as its issuer chain (nearest issuer first), up to but not
+// including the root.
 func ParseCertificatePEM(data PEM, k *PrivateKey) (c Certificate, err error) {
 	return parseCertificatePEM(data, k)
 }
@@ -39,6 +48,16 @@ func (c *certificate) GetPEM() (PEM, error) {
 	return certDERToPEM(c.der)
 }
 
+func (c *certificate) GetChainPEM() (PEM, error) {
+	var b bytes.Buffer
+	for _, der := range append([]DER{c.der}, c.issuers...) {
+		if err := pem.Encode(&b, &pem.Block{Type: "CERTIFICATE", Bytes: der}); err != nil {
+			return nil, err
+		}
+	}
+	return b.Bytes(), nil
+}
+
 func certDERToPEM(der DER) (PEM, error) {
 	var b bytes.Buffer
 	err := pem.Encode(&b, &pem.Block{
@@ -49,14 +68,27 @@ func certDERToPEM(der DER) (PEM, error) {
 }
 
 func parseCertificatePEM(data PEM, k *PrivateKey) (c *certificate, err error) {
-	var block *pem.Block
-	if block, err = decodePEMData(data); err != nil {
+	var blocks []*pem.Block
+	if blocks, err = decodeAllPEMBlocks(data); err != nil {
+		return
+	}
+	if blocks[0].Type != "CERTIFICATE" {
+		err = fmt.Errorf("PEM block is not of type CERTIFICATE: %q", blocks[0].Type)
 		return
 	}
-	if block.Type != "CERTIFICATE" {
-		err = fmt.Errorf("PEM block is not of type CERTIFICATE: %q", block.Type)
+	issuers := make([]DER, 0, len(blocks)-1)
+	for _, block := range blocks[1:] {
+		if block.Type != "CERTIFICATE" {
+			err = fmt.Errorf("PEM block is not of type CERTIFICATE: %q", block.Type)
+			return
+		}
+		issuers = append(issuers, DER(block.Bytes))
+	}
+	if c, err = parseCertificateDER(blocks[0].Bytes, k); err != nil {
+		return
 	}
-	return parseCertificateDER(block.Bytes, k)
+	c.issuers = issuers
+	return
 }
 
 func parseCertificateDER(data DER, k *PrivateKey) (c *certificate, err error) {
@@ -83,3 +115,24 @@ func decodePEMData(data []byte) (block *pem.Block, err error) {
 	}
 	return
 }
+
+// decodeAllPEMBlocks decodes every consecutive PEM block in data, in order.
+func decodeAllPEMBlocks(data []byte) (blocks []*pem.Block, err error) {
+	if len(data) == 0 {
+		err = errors.New("zero-length PEM block")
+		return
+	}
+	rest := data
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		blocks = append(blocks, block)
+	}
+	if len(blocks) == 0 {
+		err = errors.New("no PEM block could be decoded")
+	}
+	return
+}
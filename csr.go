@@ -0,0 +1,151 @@
+package badssl
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+)
+
+// PemLabelCertificateRequest is the PEM block type used for encoding and
+// decoding certificate signing requests.
+const PemLabelCertificateRequest = "CERTIFICATE REQUEST"
+
+// CSR is a certificate signing request: a public key and subject/SAN
+// fields, signed by the requester's private key, that an Authority can
+// turn into a Certificate without ever seeing that private key.
+type CSR struct {
+	csr *x509.CertificateRequest
+	der DER
+}
+
+// NewCSR generates a certificate signing request for k using the subject
+// and SAN fields of o. If k is nil, a new private key will be generated.
+func NewCSR(k *PrivateKey, o CertOptions) (c *CSR, err error) {
+	if k == nil {
+		if k, err = NewPrivateKey(RSA2048); err != nil {
+			return
+		}
+	}
+	template := &x509.CertificateRequest{
+		Subject:        o.subject(),
+		DNSNames:       o.DNSNames,
+		IPAddresses:    o.IPAddresses,
+		EmailAddresses: o.EmailAddresses,
+		URIs:           o.URIs,
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, template, k.signer)
+	if err != nil {
+		return nil, err
+	}
+	return parseCSRDER(der)
+}
+
+// ParseCSRPEM parses and loads a certificate signing request from PEM data.
+func ParseCSRPEM(data PEM) (c *CSR, err error) {
+	var block *pem.Block
+	if block, err = decodePEMData(data); err != nil {
+		return
+	}
+	if block.Type != PemLabelCertificateRequest {
+		err = fmt.Errorf("PEM block is not of type %s: %q", PemLabelCertificateRequest, block.Type)
+		return
+	}
+	return ParseCSRDER(block.Bytes)
+}
+
+// ParseCSRDER parses and loads a certificate signing request from ASN.1
+// DER data.
+func ParseCSRDER(data DER) (c *CSR, err error) {
+	return parseCSRDER(data)
+}
+
+// GetPEM implements the Encodable interface for CSR.
+// GetPEM encodes the certificate signing request in PEM format.
+func (c *CSR) GetPEM() (PEM, error) {
+	var b bytes.Buffer
+	err := pem.Encode(&b, &pem.Block{
+		Type:  PemLabelCertificateRequest,
+		Bytes: c.der,
+	})
+	return b.Bytes(), err
+}
+
+func parseCSRDER(data DER) (c *CSR, err error) {
+	var csr *x509.CertificateRequest
+	if csr, err = x509.ParseCertificateRequest(data); err != nil {
+		return
+	}
+	c = &CSR{csr: csr, der: data}
+	return
+}
+
+// SignCSR validates csr's signature and issues a certificate bound to its
+// public key, using its subject and SAN fields. o may override validity
+// (ValidFor), usages (ClientAuth), and the AIA/CRL/Must-Staple fields
+// (OCSPServers, IssuingCertificateURLs, CRLDistributionPoints, MustStaple);
+// it does not affect the subject or SANs, which are taken from csr. The
+// requester's private key is never needed: GetKey on the resulting
+// Certificate returns nil.
+func (a *authority) SignCSR(csr *CSR, o CertOptions, opts ...Option) (c Certificate, err error) {
+	if err = csr.csr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("CSR has an invalid signature: %w", err)
+	}
+	e := applyOptions(a.env, opts)
+	sn, err := randomSerialNumber(e.serialRand)
+	if err != nil {
+		return nil, err
+	}
+	ski, err := subjectKeyID(csr.csr.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	notBefore := e.notBefore()
+	notAfter := notBefore.Add(o.ValidFor)
+	extKeyUsage := []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	if o.ClientAuth {
+		extKeyUsage = append(extKeyUsage, x509.ExtKeyUsageClientAuth)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          sn,
+		Subject:               csr.csr.Subject,
+		DNSNames:              csr.csr.DNSNames,
+		IPAddresses:           csr.csr.IPAddresses,
+		EmailAddresses:        csr.csr.EmailAddresses,
+		URIs:                  csr.csr.URIs,
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              ServerKeyUsage,
+		ExtKeyUsage:           extKeyUsage,
+		BasicConstraintsValid: true,
+		SubjectKeyId:          ski,
+		AuthorityKeyId:        a.c.cert.SubjectKeyId,
+		OCSPServer:            o.OCSPServers,
+		IssuingCertificateURL: o.IssuingCertificateURLs,
+		CRLDistributionPoints: o.CRLDistributionPoints,
+	}
+	if o.MustStaple {
+		template.ExtraExtensions = append(template.ExtraExtensions, pkix.Extension{
+			Id:    oidTLSFeature,
+			Value: mustStapleFeatureValue,
+		})
+	}
+	der, err := x509.CreateCertificate(
+		e.signRand,
+		template,
+		a.c.cert,
+		csr.csr.PublicKey,
+		a.c.privateKey.signer,
+	)
+	if err != nil {
+		return nil, err
+	}
+	c = &certificate{
+		cert:    template,
+		der:     der,
+		issuers: a.chain,
+	}
+	return c, nil
+}
@@ -0,0 +1,68 @@
+package badssl
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+)
+
+func TestSignCSRChainVerifies(t *testing.T) {
+	root, err := NewAuthority(nil, CertOptions{CommonName: "root CA", ValidFor: 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("NewAuthority: %v", err)
+	}
+	requesterKey, err := NewPrivateKey(EC256)
+	if err != nil {
+		t.Fatalf("NewPrivateKey: %v", err)
+	}
+	csr, err := NewCSR(requesterKey, CertOptions{CommonName: "leaf.example.com", DNSNames: []string{"leaf.example.com"}})
+	if err != nil {
+		t.Fatalf("NewCSR: %v", err)
+	}
+	cert, err := root.SignCSR(csr, CertOptions{ValidFor: time.Hour})
+	if err != nil {
+		t.Fatalf("SignCSR: %v", err)
+	}
+	if cert.GetKey() != nil {
+		t.Fatalf("expected GetKey to be nil for a CSR-issued certificate, got %v", cert.GetKey())
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(parsePEMCert(t, mustGetPEM(t, root)))
+	leafCert := parsePEMCert(t, mustGetPEM(t, cert))
+	if _, err := leafCert.Verify(x509.VerifyOptions{
+		Roots:     roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}); err != nil {
+		t.Fatalf("signed certificate failed to verify: %v", err)
+	}
+	if leafCert.Subject.CommonName != "leaf.example.com" {
+		t.Fatalf("Subject.CommonName = %q, want %q", leafCert.Subject.CommonName, "leaf.example.com")
+	}
+}
+
+func TestSignCSRRejectsTamperedSignature(t *testing.T) {
+	root, err := NewAuthority(nil, CertOptions{CommonName: "root CA", ValidFor: 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("NewAuthority: %v", err)
+	}
+	requesterKey, err := NewPrivateKey(EC256)
+	if err != nil {
+		t.Fatalf("NewPrivateKey: %v", err)
+	}
+	csr, err := NewCSR(requesterKey, CertOptions{CommonName: "leaf.example.com"})
+	if err != nil {
+		t.Fatalf("NewCSR: %v", err)
+	}
+	der := append(DER{}, csr.der...)
+	// Flip a byte deep enough in the DER to land in the signature, not the
+	// ASN.1 structure, so CheckSignature fails rather than parsing itself.
+	der[len(der)-1] ^= 0xFF
+	tampered, err := ParseCSRDER(der)
+	if err != nil {
+		t.Fatalf("ParseCSRDER: %v", err)
+	}
+	if _, err := root.SignCSR(tampered, CertOptions{ValidFor: time.Hour}); err == nil {
+		t.Fatal("expected SignCSR to reject a CSR with a tampered signature, got nil")
+	}
+}
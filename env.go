@@ -0,0 +1,77 @@
+package badssl
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"io"
+	"time"
+)
+
+// defaultBackdate is how far before the clock's current time NotBefore is
+// set by default, to tolerate mildly skewed clocks on peers.
+const defaultBackdate = 5 * time.Minute
+
+// env holds the sources of time and randomness used during certificate
+// issuance, so that tests can pin them down and production code can leave
+// them at their defaults.
+type env struct {
+	clock            func() time.Time
+	serialRand       io.Reader
+	keyRand          io.Reader
+	signRand         io.Reader
+	backdate         time.Duration
+	parseCertificate func([]byte) (*x509.Certificate, error)
+}
+
+func newEnv() env {
+	return env{
+		clock:            time.Now,
+		serialRand:       rand.Reader,
+		keyRand:          rand.Reader,
+		signRand:         rand.Reader,
+		backdate:         defaultBackdate,
+		parseCertificate: x509.ParseCertificate,
+	}
+}
+
+func (e env) notBefore() time.Time {
+	return e.clock().Add(-e.backdate)
+}
+
+// Option configures the environment used by an Authority during certificate
+// issuance. Options passed to NewAuthority/NewIntermediate become that
+// Authority's defaults for subsequent NewCert/NewIntermediate/SignCSR calls;
+// options passed to those calls directly override the defaults for that
+// call only.
+type Option func(*env)
+
+// WithClock overrides the clock used to compute certificate validity. It is
+// primarily useful for deterministic tests.
+func WithClock(clock func() time.Time) Option {
+	return func(e *env) { e.clock = clock }
+}
+
+// WithRand overrides the randomness source used for serial numbers, key
+// generation, and signing. It is primarily useful for deterministic tests.
+func WithRand(r io.Reader) Option {
+	return func(e *env) {
+		e.serialRand = r
+		e.keyRand = r
+		e.signRand = r
+	}
+}
+
+// WithBackdate overrides how far before the clock's current time a
+// certificate's NotBefore is set. The default is 5 minutes, matching the
+// behavior of established CA implementations.
+func WithBackdate(backdate time.Duration) Option {
+	return func(e *env) { e.backdate = backdate }
+}
+
+func applyOptions(base env, opts []Option) env {
+	e := base
+	for _, opt := range opts {
+		opt(&e)
+	}
+	return e
+}
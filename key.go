@@ -2,27 +2,51 @@ package badssl
 
 import (
 	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
+	"io"
 )
 
-// RSAKeyBits is the number of bits setting used for generating keys.
-// It is exposed for reference.
+// RSAKeyBits is the size in bits of keys generated for the default KeyType,
+// RSA2048. It is exposed for reference.
 const RSAKeyBits = 2048
 
-// PublicKey is an interface that wraps an *rsa.PrivateKey and implements
-// the Encodable interface for the associated public key.
+// KeyType identifies the key algorithm (and, where applicable, size) to use
+// when generating a new PrivateKey.
+type KeyType int
+
+const (
+	// RSA2048 generates a 2048-bit RSA key. This is the default.
+	RSA2048 KeyType = iota
+	// RSA3072 generates a 3072-bit RSA key.
+	RSA3072
+	// RSA4096 generates a 4096-bit RSA key.
+	RSA4096
+	// EC256 generates an ECDSA key over the P-256 curve.
+	EC256
+	// EC384 generates an ECDSA key over the P-384 curve.
+	EC384
+	// Ed25519Key generates an Ed25519 key.
+	Ed25519Key
+)
+
+// PublicKey is an interface that wraps a crypto.PublicKey and implements
+// the Encodable interface for it.
 type PublicKey struct {
-	privateKey *rsa.PrivateKey
+	publicKey crypto.PublicKey
 }
 
 // GetPEM implements the Encodable interface for PublicKey.
 // GetPEM encodes the associated public key in PEM format.
 func (k *PublicKey) GetPEM() (PEM, error) {
-	der, err := x509.MarshalPKIXPublicKey(k.privateKey)
+	der, err := x509.MarshalPKIXPublicKey(k.publicKey)
 	if err != nil {
 		return nil, err
 	}
@@ -34,59 +58,134 @@ func (k *PublicKey) GetPEM() (PEM, error) {
 	return b.Bytes(), err
 }
 
-// PrivateKey is an interface that wraps an *rsa.PrivateKey and implements
+// PrivateKey is an interface that wraps a crypto.Signer and implements
 // the Encodable interface for it.
 type PrivateKey struct {
-	privateKey *rsa.PrivateKey
+	signer crypto.Signer
+}
+
+// NewPrivateKey generates a new private key of the given KeyType.
+func NewPrivateKey(t KeyType) (*PrivateKey, error) {
+	return newPrivateKeyFromRand(t, rand.Reader)
 }
 
-// NewPrivateKey generates a new RSA private key with default settings.
-func NewPrivateKey() (*PrivateKey, error) {
-	k, err := newKey()
+func newPrivateKeyFromRand(t KeyType, rnd io.Reader) (*PrivateKey, error) {
+	s, err := newKey(t, rnd)
 	if err != nil {
 		return nil, err
 	}
-	return &PrivateKey{privateKey: k}, nil
+	return &PrivateKey{signer: s}, nil
 }
 
-// ParseKeyPEM parses and loads a private key from PEM data.
+// ParseKeyPEM parses and loads a private key from PEM data. The PEM block
+// type is used to determine the encoding of the key: "RSA PRIVATE KEY"
+// (PKCS#1), "EC PRIVATE KEY" (SEC 1), or "PRIVATE KEY" (PKCS#8).
 func ParseKeyPEM(data PEM) (k *PrivateKey, err error) {
 	var block *pem.Block
 	if block, err = decodePEMData(data); err != nil {
 		return
 	}
-	if block.Type != "RSA PRIVATE KEY" {
-		err = fmt.Errorf("PEM block is not of type RSA PRIVATE KEY: %q", block.Type)
+	var signer crypto.Signer
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		signer, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		signer, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		var key any
+		if key, err = x509.ParsePKCS8PrivateKey(block.Bytes); err != nil {
+			return
+		}
+		var ok bool
+		if signer, ok = key.(crypto.Signer); !ok {
+			err = fmt.Errorf("PKCS#8 key of type %T does not implement crypto.Signer", key)
+		}
+	case PemLabelEncryptedPrivateKey:
+		err = fmt.Errorf("PEM block is password-encrypted; use ParseEncryptedKeyPEM instead")
+	default:
+		err = fmt.Errorf("PEM block is not a supported private key type: %q", block.Type)
+	}
+	if err != nil {
 		return
 	}
-	return ParseKeyDER(block.Bytes)
+	return &PrivateKey{signer: signer}, nil
 }
 
-// ParseKeyDER parses and loads a private key from ASN.1 DER data.
+// ParseKeyDER parses and loads a private key from ASN.1 DER data. Since DER
+// data carries no block type, the PKCS#1, SEC 1, and PKCS#8 encodings are
+// each tried in turn until one succeeds.
 func ParseKeyDER(data DER) (k *PrivateKey, err error) {
-	var key *rsa.PrivateKey
-	if key, err = x509.ParsePKCS1PrivateKey(data); err != nil {
-		return
+	if key, perr := x509.ParsePKCS1PrivateKey(data); perr == nil {
+		return &PrivateKey{signer: key}, nil
+	}
+	if key, perr := x509.ParseECPrivateKey(data); perr == nil {
+		return &PrivateKey{signer: key}, nil
+	}
+	key, perr := x509.ParsePKCS8PrivateKey(data)
+	if perr != nil {
+		return nil, fmt.Errorf("could not parse DER as PKCS#1, EC, or PKCS#8 private key: %w", perr)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("PKCS#8 key of type %T does not implement crypto.Signer", key)
 	}
-	return &PrivateKey{privateKey: key}, nil
+	return &PrivateKey{signer: signer}, nil
 }
 
 // GetPEM implements the Encodable interface for PrivateKey.
-// GetPEM encodes the associated private key in PEM format.
+// GetPEM encodes the associated private key in PEM format as PKCS#8 under
+// a "PRIVATE KEY" block. Use GetLegacyPEM for the legacy PKCS#1 encoding of
+// RSA keys.
 func (k *PrivateKey) GetPEM() (PEM, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(k.signer)
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	err = pem.Encode(&b, &pem.Block{
+		Type:  "PRIVATE KEY",
+		Bytes: der,
+	})
+	return b.Bytes(), err
+}
+
+// GetLegacyPEM encodes the associated private key in the legacy PKCS#1
+// "RSA PRIVATE KEY" format for back-compat with tools that do not
+// understand PKCS#8. It returns an error if the key is not RSA.
+func (k *PrivateKey) GetLegacyPEM() (PEM, error) {
+	rsaKey, ok := k.signer.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("legacy PKCS#1 PEM is only supported for RSA keys, not %T", k.signer)
+	}
 	var b bytes.Buffer
 	err := pem.Encode(&b, &pem.Block{
 		Type:  "RSA PRIVATE KEY",
-		Bytes: x509.MarshalPKCS1PrivateKey(k.privateKey),
+		Bytes: x509.MarshalPKCS1PrivateKey(rsaKey),
 	})
 	return b.Bytes(), err
 }
 
 // Public retrieves the public key from PrivateKey.
 func (k *PrivateKey) Public() *PublicKey {
-	return &PublicKey{privateKey: k.privateKey}
+	return &PublicKey{publicKey: k.signer.Public()}
 }
 
-func newKey() (*rsa.PrivateKey, error) {
-	return rsa.GenerateKey(rand.Reader, RSAKeyBits)
+func newKey(t KeyType, rnd io.Reader) (crypto.Signer, error) {
+	switch t {
+	case RSA2048:
+		return rsa.GenerateKey(rnd, 2048)
+	case RSA3072:
+		return rsa.GenerateKey(rnd, 3072)
+	case RSA4096:
+		return rsa.GenerateKey(rnd, 4096)
+	case EC256:
+		return ecdsa.GenerateKey(elliptic.P256(), rnd)
+	case EC384:
+		return ecdsa.GenerateKey(elliptic.P384(), rnd)
+	case Ed25519Key:
+		_, priv, err := ed25519.GenerateKey(rnd)
+		return priv, err
+	default:
+		return nil, fmt.Errorf("unknown KeyType: %v", t)
+	}
 }
@@ -0,0 +1,76 @@
+package badssl
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNewPrivateKeyRoundTripsEveryKeyType(t *testing.T) {
+	for _, kt := range []KeyType{RSA2048, RSA3072, RSA4096, EC256, EC384, Ed25519Key} {
+		kt := kt
+		t.Run(keyTypeName(kt), func(t *testing.T) {
+			k, err := NewPrivateKey(kt)
+			if err != nil {
+				t.Fatalf("NewPrivateKey: %v", err)
+			}
+			pem, err := k.GetPEM()
+			if err != nil {
+				t.Fatalf("GetPEM: %v", err)
+			}
+			parsed, err := ParseKeyPEM(pem)
+			if err != nil {
+				t.Fatalf("ParseKeyPEM: %v", err)
+			}
+			parsedPEM, err := parsed.GetPEM()
+			if err != nil {
+				t.Fatalf("GetPEM (parsed): %v", err)
+			}
+			if !bytes.Equal(pem, parsedPEM) {
+				t.Fatalf("parsed key does not round trip:\ngot  %s\nwant %s", parsedPEM, pem)
+			}
+		})
+	}
+}
+
+func keyTypeName(kt KeyType) string {
+	switch kt {
+	case RSA2048:
+		return "RSA2048"
+	case RSA3072:
+		return "RSA3072"
+	case RSA4096:
+		return "RSA4096"
+	case EC256:
+		return "EC256"
+	case EC384:
+		return "EC384"
+	case Ed25519Key:
+		return "Ed25519Key"
+	default:
+		return "unknown"
+	}
+}
+
+func TestGetLegacyPEMRejectsNonRSA(t *testing.T) {
+	k, err := NewPrivateKey(EC256)
+	if err != nil {
+		t.Fatalf("NewPrivateKey: %v", err)
+	}
+	if _, err := k.GetLegacyPEM(); err == nil {
+		t.Fatal("expected GetLegacyPEM to reject a non-RSA key, got nil")
+	}
+}
+
+func TestGetLegacyPEMRoundTripsRSA(t *testing.T) {
+	k, err := NewPrivateKey(RSA2048)
+	if err != nil {
+		t.Fatalf("NewPrivateKey: %v", err)
+	}
+	pem, err := k.GetLegacyPEM()
+	if err != nil {
+		t.Fatalf("GetLegacyPEM: %v", err)
+	}
+	if _, err := ParseKeyPEM(pem); err != nil {
+		t.Fatalf("ParseKeyPEM(legacy): %v", err)
+	}
+}
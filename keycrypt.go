@@ -0,0 +1,176 @@
+package badssl
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"strconv"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// PemLabelEncryptedPrivateKey is the PEM block type used for
+// password-encrypted private keys produced by GetEncryptedPEM.
+const PemLabelEncryptedPrivateKey = "ENCRYPTED PRIVATE KEY"
+
+// scrypt parameters for the interactive (passphrase-unlocking-a-local-key)
+// use case. See RFC 7914 for guidance on choosing these.
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// GetEncryptedPEM marshals k as PKCS#8 and encrypts it with a key derived
+// from passphrase via scrypt, sealed with AES-GCM. x509.EncryptPEMBlock is
+// deliberately not used: it is deprecated and its PBKDF1-like construction
+// is weak by modern standards. cipher selects the AES key size (128/192/256
+// bits); DES variants are not supported. The scrypt salt, parameters, and
+// GCM nonce are stored in the PEM block's headers so ParseEncryptedKeyPEM
+// can recover them.
+func (k *PrivateKey) GetEncryptedPEM(passphrase []byte, cipher x509.PEMCipher) (PEM, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(k.signer)
+	if err != nil {
+		return nil, err
+	}
+	keyLen, err := aesKeyLen(cipher)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err = rand.Read(salt); err != nil {
+		return nil, err
+	}
+	dk, err := scrypt.Key(passphrase, salt, scryptN, scryptR, scryptP, keyLen)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, nonce, err := seal(dk, der)
+	if err != nil {
+		return nil, err
+	}
+	block := &pem.Block{
+		Type: PemLabelEncryptedPrivateKey,
+		Headers: map[string]string{
+			"Salt":       hex.EncodeToString(salt),
+			"Nonce":      hex.EncodeToString(nonce),
+			"Key-Length": strconv.Itoa(keyLen),
+			"N":          strconv.Itoa(scryptN),
+			"R":          strconv.Itoa(scryptR),
+			"P":          strconv.Itoa(scryptP),
+		},
+		Bytes: ciphertext,
+	}
+	return pem.EncodeToMemory(block), nil
+}
+
+// ParseEncryptedKeyPEM parses and decrypts a private key produced by
+// GetEncryptedPEM, deriving the decryption key from passphrase with the
+// scrypt parameters stored in the PEM block's headers.
+func ParseEncryptedKeyPEM(data PEM, passphrase []byte) (k *PrivateKey, err error) {
+	var block *pem.Block
+	if block, err = decodePEMData(data); err != nil {
+		return
+	}
+	if block.Type != PemLabelEncryptedPrivateKey {
+		err = fmt.Errorf("PEM block is not of type %s: %q", PemLabelEncryptedPrivateKey, block.Type)
+		return
+	}
+	salt, nonce, keyLen, n, r, p, err := parseEncryptionHeaders(block.Headers)
+	if err != nil {
+		return
+	}
+	dk, err := scrypt.Key(passphrase, salt, n, r, p, keyLen)
+	if err != nil {
+		return
+	}
+	der, err := open(dk, nonce, block.Bytes)
+	if err != nil {
+		err = fmt.Errorf("could not decrypt private key (wrong passphrase?): %w", err)
+		return
+	}
+	var key any
+	if key, err = x509.ParsePKCS8PrivateKey(der); err != nil {
+		return
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		err = fmt.Errorf("PKCS#8 key of type %T does not implement crypto.Signer", key)
+		return
+	}
+	return &PrivateKey{signer: signer}, nil
+}
+
+func aesKeyLen(c x509.PEMCipher) (int, error) {
+	switch c {
+	case x509.PEMCipherAES128:
+		return 16, nil
+	case x509.PEMCipherAES192:
+		return 24, nil
+	case x509.PEMCipherAES256:
+		return 32, nil
+	default:
+		return 0, fmt.Errorf("unsupported PEM cipher %v: only AES-128/192/256 are supported", c)
+	}
+}
+
+func seal(key, plaintext []byte) (ciphertext, nonce []byte, err error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce = make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+	return gcm.Seal(nil, nonce, plaintext, nil), nonce, nil
+}
+
+func open(key, nonce, ciphertext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func parseEncryptionHeaders(headers map[string]string) (salt, nonce []byte, keyLen, n, r, p int, err error) {
+	if salt, err = hex.DecodeString(headers["Salt"]); err != nil {
+		err = fmt.Errorf("invalid Salt header: %w", err)
+		return
+	}
+	if nonce, err = hex.DecodeString(headers["Nonce"]); err != nil {
+		err = fmt.Errorf("invalid Nonce header: %w", err)
+		return
+	}
+	if keyLen, err = strconv.Atoi(headers["Key-Length"]); err != nil {
+		err = fmt.Errorf("invalid Key-Length header: %w", err)
+		return
+	}
+	if n, err = strconv.Atoi(headers["N"]); err != nil {
+		err = fmt.Errorf("invalid N header: %w", err)
+		return
+	}
+	if r, err = strconv.Atoi(headers["R"]); err != nil {
+		err = fmt.Errorf("invalid R header: %w", err)
+		return
+	}
+	if p, err = strconv.Atoi(headers["P"]); err != nil {
+		err = fmt.Errorf("invalid P header: %w", err)
+		return
+	}
+	return
+}
@@ -0,0 +1,80 @@
+package badssl
+
+import (
+	"crypto/x509"
+	"strings"
+	"testing"
+)
+
+func TestEncryptedPEMRoundTrip(t *testing.T) {
+	k, err := NewPrivateKey(EC256)
+	if err != nil {
+		t.Fatalf("NewPrivateKey: %v", err)
+	}
+	encrypted, err := k.GetEncryptedPEM([]byte("correct horse battery staple"), x509.PEMCipherAES256)
+	if err != nil {
+		t.Fatalf("GetEncryptedPEM: %v", err)
+	}
+	got, err := ParseEncryptedKeyPEM(encrypted, []byte("correct horse battery staple"))
+	if err != nil {
+		t.Fatalf("ParseEncryptedKeyPEM: %v", err)
+	}
+	wantPEM, err := k.GetPEM()
+	if err != nil {
+		t.Fatalf("GetPEM: %v", err)
+	}
+	gotPEM, err := got.GetPEM()
+	if err != nil {
+		t.Fatalf("GetPEM: %v", err)
+	}
+	if string(gotPEM) != string(wantPEM) {
+		t.Fatalf("decrypted key does not match original:\ngot  %s\nwant %s", gotPEM, wantPEM)
+	}
+}
+
+func TestParseEncryptedKeyPEMWrongPassphrase(t *testing.T) {
+	k, err := NewPrivateKey(EC256)
+	if err != nil {
+		t.Fatalf("NewPrivateKey: %v", err)
+	}
+	encrypted, err := k.GetEncryptedPEM([]byte("correct passphrase"), x509.PEMCipherAES256)
+	if err != nil {
+		t.Fatalf("GetEncryptedPEM: %v", err)
+	}
+	if _, err := ParseEncryptedKeyPEM(encrypted, []byte("wrong passphrase")); err == nil {
+		t.Fatal("expected an error decrypting with the wrong passphrase, got nil")
+	}
+}
+
+func TestParseEncryptedKeyPEMMalformedHeaders(t *testing.T) {
+	k, err := NewPrivateKey(EC256)
+	if err != nil {
+		t.Fatalf("NewPrivateKey: %v", err)
+	}
+	encrypted, err := k.GetEncryptedPEM([]byte("passphrase"), x509.PEMCipherAES256)
+	if err != nil {
+		t.Fatalf("GetEncryptedPEM: %v", err)
+	}
+
+	t.Run("bad hex salt", func(t *testing.T) {
+		corrupted := strings.Replace(string(encrypted), "Salt: ", "Salt: zz", 1)
+		if _, err := ParseEncryptedKeyPEM(PEM(corrupted), []byte("passphrase")); err == nil {
+			t.Fatal("expected an error parsing a malformed Salt header, got nil")
+		}
+	})
+
+	t.Run("missing header", func(t *testing.T) {
+		lines := strings.Split(string(encrypted), "\n")
+		filtered := lines[:0]
+		for _, line := range lines {
+			if strings.HasPrefix(line, "N:") {
+				continue
+			}
+			filtered = append(filtered, line)
+		}
+		missing := strings.Join(filtered, "\n")
+		if _, err := ParseEncryptedKeyPEM(PEM(missing), []byte("passphrase")); err == nil {
+			t.Fatal("expected an error parsing a PEM block missing the N header, got nil")
+		}
+	})
+}
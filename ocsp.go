@@ -0,0 +1,40 @@
+package badssl
+
+import (
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// certX509 returns the underlying *x509.Certificate for a Certificate
+// produced by this package.
+func certX509(c Certificate) (*x509.Certificate, error) {
+	switch v := c.(type) {
+	case *certificate:
+		return v.cert, nil
+	case *authority:
+		return v.c.cert, nil
+	default:
+		return nil, fmt.Errorf("unsupported Certificate implementation %T", c)
+	}
+}
+
+// SignOCSPResponse signs an OCSP response for cert with the given status
+// (an ocsp.Good/ocsp.Revoked/ocsp.Unknown constant) and validity window,
+// so that a, or anything spinning up a fake CA for tests, can also stand up
+// a matching OCSP responder.
+func (a *authority) SignOCSPResponse(cert Certificate, status int, thisUpdate, nextUpdate time.Time) ([]byte, error) {
+	leaf, err := certX509(cert)
+	if err != nil {
+		return nil, err
+	}
+	template := ocsp.Response{
+		Status:       status,
+		SerialNumber: leaf.SerialNumber,
+		ThisUpdate:   thisUpdate,
+		NextUpdate:   nextUpdate,
+	}
+	return ocsp.CreateResponse(a.c.cert, a.c.cert, template, a.c.privateKey.signer)
+}
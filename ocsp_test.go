@@ -0,0 +1,42 @@
+package badssl
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+func TestSignOCSPResponseVerifiesAgainstIssuer(t *testing.T) {
+	root, err := NewAuthority(nil, CertOptions{CommonName: "root CA", ValidFor: 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("NewAuthority: %v", err)
+	}
+	leafKey, err := NewPrivateKey(EC256)
+	if err != nil {
+		t.Fatalf("NewPrivateKey: %v", err)
+	}
+	leaf, err := root.NewCert(leafKey, CertOptions{CommonName: "leaf.example.com", ValidFor: time.Hour})
+	if err != nil {
+		t.Fatalf("NewCert: %v", err)
+	}
+
+	now := time.Now()
+	respDER, err := root.SignOCSPResponse(leaf, ocsp.Good, now, now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("SignOCSPResponse: %v", err)
+	}
+
+	issuerCert := parsePEMCert(t, mustGetPEM(t, root))
+	resp, err := ocsp.ParseResponseForCert(respDER, nil, issuerCert)
+	if err != nil {
+		t.Fatalf("ocsp.ParseResponseForCert: %v", err)
+	}
+	if resp.Status != ocsp.Good {
+		t.Fatalf("resp.Status = %v, want ocsp.Good", resp.Status)
+	}
+	leafCert := parsePEMCert(t, mustGetPEM(t, leaf))
+	if resp.SerialNumber.Cmp(leafCert.SerialNumber) != 0 {
+		t.Fatalf("resp.SerialNumber = %v, want %v", resp.SerialNumber, leafCert.SerialNumber)
+	}
+}